@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/motemen/ghq/utils"
+)
+
+var BackupCommand = cli.Command{
+	Name:   "backup",
+	Usage:  "Back up a remote repository as a bare mirror with rotating snapshots",
+	Action: DoBackup,
+	Flags: []cli.Flag{
+		cli.BoolFlag{"bare", "Clone as a bare mirror (git clone --mirror)"},
+		cli.BoolFlag{"structured", "Force the <host>/<owner>/<name> on-disk layout"},
+		cli.IntFlag{"keep", 0, "Keep only the newest N snapshots under <name>/<unix-ts>"},
+		cli.StringFlag{"from-list", "", "Read one repository URL per line from `FILE`"},
+		cli.BoolFlag{"lfs", "Fetch Git LFS objects after clone (git lfs fetch --all)"},
+		jobsFlag,
+		protocolFlag,
+	},
+}
+
+func DoBackup(c *cli.Context) {
+	bare := c.Bool("bare")
+	structured := c.Bool("structured")
+	keep := c.Int("keep")
+	fromList := c.String("from-list")
+	lfs := c.Bool("lfs")
+	jobs := c.Int("jobs")
+	protocol := resolveProtocol(c)
+
+	argURLs := []string(c.Args())
+	if fromList != "" {
+		urls, err := readURLList(fromList)
+		utils.DieIf(err)
+		argURLs = append(argURLs, urls...)
+	}
+
+	if len(argURLs) == 0 {
+		cli.ShowCommandHelp(c, "backup")
+		os.Exit(1)
+	}
+
+	if structured {
+		utils.Log("warn", "--structured has no effect: <host>/<owner>/<name> is the only layout ghq supports")
+	}
+
+	queue := newCloneQueue(jobs)
+
+	for _, argURL := range argURLs {
+		backupOne(argURL, bare, keep, lfs, protocol, queue)
+	}
+
+	queue.Wait()
+}
+
+func readURLList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	urls := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, scanner.Err()
+}
+
+// backupOne resolves argURL to a target directory and enqueues the actual
+// clone/lfs/prune work on queue. Resolution happens synchronously here
+// (cheap, no network) so the in-flight-path guard has a path to key on
+// before any worker picks the job up.
+func backupOne(argURL string, bare bool, keep int, lfs bool, protocol string, queue *cloneQueue) {
+	url, err := url.Parse(argURL)
+	if utils.ErrorIf(err) {
+		return
+	}
+
+	if !url.IsAbs() {
+		url.Scheme = "https"
+		url.Host = "github.com"
+		if url.Path[0] != '/' {
+			url.Path = "/" + url.Path
+		}
+	}
+
+	remote, err := NewRemoteRepository(url)
+	if utils.ErrorIf(err) {
+		return
+	}
+
+	if remote.IsValid() == false {
+		utils.Log("error", fmt.Sprintf("Not a valid repository: %s", url))
+		return
+	}
+
+	remoteURL := remote.URL()
+	pathParts := append(
+		[]string{remoteURL.Host}, strings.Split(remoteURL.Path, "/")...,
+	)
+	local := LocalRepositoryFromPathParts(pathParts)
+
+	targetPath := local.FullPath
+	if bare {
+		targetPath = targetPath + ".git"
+	}
+
+	if keep > 0 {
+		snapshotDir, err := newSnapshotDir(targetPath)
+		utils.PanicIf(err)
+		targetPath = snapshotDir
+	}
+
+	queue.Enqueue(targetPath, func(log func(tag, message string)) {
+		exists := true
+		if _, err := os.Stat(targetPath); err != nil {
+			if !os.IsNotExist(err) {
+				utils.PanicIf(err)
+			}
+			exists = false
+		}
+
+		if exists {
+			log("update", targetPath)
+			if bare {
+				utils.DieIf(utils.Run("git", "--git-dir="+targetPath, "remote", "update", "--prune"))
+			} else {
+				local.VCS().Update(targetPath)
+			}
+		} else {
+			cloneURL := remoteURL
+			if protocol == "ssh" {
+				utils.DieIf(checkSSHAuth(remoteURL.Host))
+			}
+			if protocol != "" && protocol != "https" {
+				var err error
+				cloneURL, err = rewriteURLForProtocol(remoteURL, protocol)
+				utils.DieIf(err)
+			}
+
+			log("backup", fmt.Sprintf("%s -> %s", cloneURL, targetPath))
+
+			if bare {
+				utils.DieIf(utils.Run("git", "clone", "--mirror", cloneURL.String(), targetPath))
+			} else {
+				remote.VCS().Clone(cloneURL, targetPath)
+			}
+		}
+
+		if lfs {
+			var output bytes.Buffer
+			cmd := exec.Command("git", "lfs", "fetch", "--all")
+			cmd.Dir = targetPath
+			cmd.Stdout = &output
+			cmd.Stderr = &output
+			utils.DieIf(cmd.Run())
+			if output.Len() > 0 {
+				log("lfs", strings.TrimSpace(output.String()))
+			}
+		}
+
+		if keep > 0 {
+			utils.PanicIf(pruneSnapshots(filepath.Dir(targetPath), keep))
+		}
+	})
+}
+
+// newSnapshotDir returns the path base/<unix-ts> for a new snapshot. Only
+// base is created; the snapshot directory itself is left for the clone to
+// create, since backupOne treats an existing target as one to update.
+// Clones are written directly into this path rather than renamed into place
+// afterwards.
+func newSnapshotDir(base string) (string, error) {
+	dir := filepath.Join(base, strconv.FormatInt(time.Now().Unix(), 10))
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// pruneSnapshots removes all but the keep most recently modified entries of
+// dir, sorted by mtime then by name to stay deterministic on filesystems
+// whose mtime resolution is coarser than a second.
+func pruneSnapshots(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		ti, tj := entries[i].ModTime(), entries[j].ModTime()
+		if ti.Equal(tj) {
+			return entries[i].Name() > entries[j].Name()
+		}
+		return ti.After(tj)
+	})
+
+	if len(entries) <= keep {
+		return nil
+	}
+
+	for _, entry := range entries[keep:] {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func touchSnapshot(t *testing.T, dir, name string, mtime time.Time) {
+	path := filepath.Join(dir, name)
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneSnapshotsKeepOne(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghq-backup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := time.Now()
+	touchSnapshot(t, dir, "1000000000", base)
+	touchSnapshot(t, dir, "1000000100", base.Add(100*time.Second))
+	touchSnapshot(t, dir, "1000000200", base.Add(200*time.Second))
+
+	if err := pruneSnapshots(dir, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 snapshot to remain, got %d", len(entries))
+	}
+	if entries[0].Name() != "1000000200" {
+		t.Errorf("expected newest snapshot to survive, got %q", entries[0].Name())
+	}
+}
+
+func TestPruneSnapshotsKeepThree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghq-backup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := time.Now()
+	names := []string{"1000000000", "1000000100", "1000000200", "1000000300", "1000000400"}
+	for i, name := range names {
+		touchSnapshot(t, dir, name, base.Add(time.Duration(i)*100*time.Second))
+	}
+
+	if err := pruneSnapshots(dir, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 snapshots to remain, got %d", len(entries))
+	}
+
+	want := map[string]bool{"1000000200": true, "1000000300": true, "1000000400": true}
+	for _, entry := range entries {
+		if !want[entry.Name()] {
+			t.Errorf("unexpected snapshot survived: %q", entry.Name())
+		}
+	}
+}
+
+// TestPruneSnapshotsMtimeSkew exercises filesystems that truncate mtimes to
+// whole seconds, where two snapshots can land on an identical ModTime; the
+// directory name (a Unix timestamp) must then break the tie.
+func TestPruneSnapshotsMtimeSkew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghq-backup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	same := time.Now().Truncate(time.Second)
+	touchSnapshot(t, dir, "1000000100", same)
+	touchSnapshot(t, dir, "1000000200", same)
+	touchSnapshot(t, dir, "1000000300", same)
+
+	if err := pruneSnapshots(dir, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 snapshots to remain, got %d", len(entries))
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == "1000000100" {
+			t.Errorf("expected oldest-named snapshot to be pruned on tie, but %q survived", entry.Name())
+		}
+	}
+}
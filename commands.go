@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"runtime"
 	"strings"
 	"syscall"
 
@@ -13,12 +14,17 @@ import (
 	"github.com/motemen/ghq/utils"
 )
 
+var jobsFlag = cli.IntFlag{"jobs, j", runtime.NumCPU(), "Number of concurrent clone/update workers (1 for sequential)"}
+
 var Commands = []cli.Command{
 	GetCommand,
 	ListCommand,
 	LookCommand,
 	StarredGommand,
 	PocketCommand,
+	BackupCommand,
+	OrgCommand,
+	ExportCommand,
 }
 
 var GetCommand = cli.Command{
@@ -27,6 +33,7 @@ var GetCommand = cli.Command{
 	Action: DoGet,
 	Flags: []cli.Flag{
 		cli.BoolFlag{"update, u", "Update local repository if cloned already"},
+		protocolFlag,
 	},
 }
 
@@ -53,6 +60,8 @@ var StarredGommand = cli.Command{
 	Action: DoStarred,
 	Flags: []cli.Flag{
 		cli.BoolFlag{"update, u", "Update local repository if cloned already"},
+		jobsFlag,
+		protocolFlag,
 	},
 }
 
@@ -62,6 +71,8 @@ var PocketCommand = cli.Command{
 	Action: DoPocket,
 	Flags: []cli.Flag{
 		cli.BoolFlag{"update, u", "Update local repository if cloned already"},
+		jobsFlag,
+		protocolFlag,
 	},
 }
 
@@ -71,6 +82,9 @@ var commandArguments = map[string]string{
 	"look":    "<project> | <user>/<project> | <host>/<user>/<project>",
 	"starred": "[-u] <user>",
 	"pocket":  "[-u]",
+	"backup":  "[--bare] [--structured] [--keep=N] [--from-list=<file>] [--lfs] <repository URL> | <user>/<project>",
+	"org":     "[-u] [--include=<glob>]... [--exclude=<glob>]... [--include-forks] [--include-archived] <org-or-user>",
+	"export":  "[--bare] [--out=<dir>] [--include-git] <query>",
 }
 
 func init() {
@@ -124,15 +138,35 @@ func DoGet(c *cli.Context) {
 		os.Exit(1)
 	}
 
-	getRemoteRepository(remote, doUpdate)
+	getRemoteRepositoryWithLogger(remote, doUpdate, resolveProtocol(c), utils.Log)
 }
 
-func getRemoteRepository(remote RemoteRepository, doUpdate bool) {
+func localRepositoryFor(remote RemoteRepository) *LocalRepository {
 	remoteURL := remote.URL()
 	pathParts := append(
 		[]string{remoteURL.Host}, strings.Split(remoteURL.Path, "/")...,
 	)
-	local := LocalRepositoryFromPathParts(pathParts)
+	return LocalRepositoryFromPathParts(pathParts)
+}
+
+func getRemoteRepository(remote RemoteRepository, doUpdate bool) {
+	getRemoteRepositoryWithLogger(remote, doUpdate, "https", utils.Log)
+}
+
+// getRemoteRepositoryWithLogger is the work done per repository by both the
+// sequential commands and cloneQueue's workers. Logging goes through log
+// rather than straight to utils.Log so a worker can buffer its lines and
+// flush them atomically once the job is done. protocol rewrites the clone
+// URL's transport (https, ssh or git) without affecting the on-disk path,
+// which is always derived from the original https-style host/owner/name.
+func getRemoteRepositoryWithLogger(remote RemoteRepository, doUpdate bool, protocol string, log func(tag, message string)) {
+	remoteURL := remote.URL()
+
+	if globalRepoCache.seenBefore(canonicalRepoKey(remoteURL)) {
+		return
+	}
+
+	local := localRepositoryFor(remote)
 
 	path := local.FullPath
 	newPath := false
@@ -147,14 +181,23 @@ func getRemoteRepository(remote RemoteRepository, doUpdate bool) {
 	}
 
 	if newPath {
-		utils.Log("clone", fmt.Sprintf("%s -> %s", remoteURL, path))
-		remote.VCS().Clone(remoteURL, path)
+		cloneURL := remoteURL
+		if protocol == "ssh" {
+			utils.DieIf(checkSSHAuth(remoteURL.Host))
+		}
+		if protocol != "" && protocol != "https" {
+			cloneURL, err = rewriteURLForProtocol(remoteURL, protocol)
+			utils.DieIf(err)
+		}
+
+		log("clone", fmt.Sprintf("%s -> %s", cloneURL, path))
+		remote.VCS().Clone(cloneURL, path)
 	} else {
 		if doUpdate {
-			utils.Log("update", path)
+			log("update", path)
 			local.VCS().Update(path)
 		} else {
-			utils.Log("exists", path)
+			log("exists", path)
 		}
 	}
 }
@@ -264,8 +307,10 @@ func DoStarred(c *cli.Context) {
 		os.Exit(1)
 	}
 
-	client := github.NewClient(nil)
+	client := authenticatedGithubClient()
 	options := &github.ActivityListStarredOptions{Sort: "created"}
+	queue := newCloneQueue(c.Int("jobs"))
+	protocol := resolveProtocol(c)
 
 	for page := 1; ; page++ {
 		options.Page = page
@@ -291,13 +336,15 @@ func DoStarred(c *cli.Context) {
 				continue
 			}
 
-			getRemoteRepository(remote, c.Bool("update"))
+			queue.EnqueueClone(remote, c.Bool("update"), protocol)
 		}
 
 		if page == res.LastPage {
 			break
 		}
 	}
+
+	queue.Wait()
 }
 
 func DoPocket(c *cli.Context) {
@@ -333,6 +380,9 @@ func DoPocket(c *cli.Context) {
 	res, err := pocket.RetrieveGitHubEntries(accessToken)
 	utils.DieIf(err)
 
+	queue := newCloneQueue(c.Int("jobs"))
+	protocol := resolveProtocol(c)
+
 	for _, item := range res.List {
 		url, err := url.Parse(item.ResolvedURL)
 		if err != nil {
@@ -350,6 +400,8 @@ func DoPocket(c *cli.Context) {
 			continue
 		}
 
-		getRemoteRepository(remote, c.Bool("update"))
+		queue.EnqueueClone(remote, c.Bool("update"), protocol)
 	}
+
+	queue.Wait()
 }
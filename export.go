@@ -0,0 +1,182 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/motemen/ghq/utils"
+)
+
+var ExportCommand = cli.Command{
+	Name:   "export",
+	Usage:  "Export matching local repositories as zip archives",
+	Action: DoExport,
+	Flags: []cli.Flag{
+		cli.BoolFlag{"bare", "Treat matches as bare/mirror clones and zip the working directory tree"},
+		cli.BoolFlag{"exact, e", "Perform an exact match"},
+		cli.StringFlag{"out", ".", "Directory to write archives into"},
+		cli.BoolFlag{"include-git", "Include pack files (objects/pack/*.pack) when --bare is set"},
+	},
+}
+
+func DoExport(c *cli.Context) {
+	query := c.Args().First()
+	bare := c.Bool("bare")
+	exact := c.Bool("exact")
+	outDir := c.String("out")
+	includeGit := c.Bool("include-git")
+
+	if query == "" {
+		cli.ShowCommandHelp(c, "export")
+		os.Exit(1)
+	}
+
+	var filterFn func(*LocalRepository) bool
+	if exact {
+		filterFn = func(repo *LocalRepository) bool {
+			return repo.Matches(query)
+		}
+	} else {
+		filterFn = func(repo *LocalRepository) bool {
+			return strings.Contains(repo.NonHostPath(), query)
+		}
+	}
+
+	repos := []*LocalRepository{}
+	walkLocalRepositories(func(repo *LocalRepository) {
+		if filterFn(repo) {
+			repos = append(repos, repo)
+		}
+	})
+
+	if len(repos) == 0 {
+		utils.Log("error", "No repository found")
+		return
+	}
+
+	utils.PanicIf(os.MkdirAll(outDir, 0755))
+
+	for _, repo := range repos {
+		exportOne(repo, bare, outDir, includeGit)
+	}
+}
+
+func exportOne(repo *LocalRepository, bare bool, outDir string, includeGit bool) {
+	shortSHA, err := repoShortSHA(repo.FullPath)
+	if utils.ErrorIf(err) {
+		return
+	}
+
+	owner, name := ownerAndRepoName(repo)
+	archivePath := filepath.Join(outDir, fmt.Sprintf("%s-%s-%s.zip", owner, name, shortSHA))
+
+	if bare {
+		err = zipWorkingTree(repo.FullPath, archivePath, includeGit)
+	} else {
+		err = archiveWithGit(repo.FullPath, archivePath)
+	}
+	if utils.ErrorIf(err) {
+		return
+	}
+
+	fmt.Println(archivePath)
+}
+
+func ownerAndRepoName(repo *LocalRepository) (string, string) {
+	parts := repo.PathParts
+	if len(parts) < 2 {
+		return "", repo.RelPath
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
+
+func repoShortSHA(path string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
+	cmd.Dir = path
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// archiveWithGit streams `git archive --format=zip HEAD`, which is already a
+// valid zip file, straight to archivePath.
+func archiveWithGit(repoPath, archivePath string) error {
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	cmd := exec.Command("git", "archive", "--format=zip", "HEAD")
+	cmd.Dir = repoPath
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// zipWorkingTree is the --bare fallback: a bare/mirror clone has no checked
+// out working tree for `git archive` to read, so the repository's directory
+// is zipped directly instead, skipping pack files by default since they
+// duplicate the object data gickup-style mirrors already keep elsewhere.
+// root here is the bare repository directory itself (e.g. foo.git), so its
+// object store lives directly at objects/pack, not .git/objects/pack.
+func zipWorkingTree(root, archivePath string, includeGit bool) error {
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	defer zw.Close()
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		if !includeGit && isGitPackFile(rel) {
+			return nil
+		}
+
+		if info.IsDir() {
+			_, err := zw.Create(rel + "/")
+			return err
+		}
+
+		entry, err := zw.Create(rel)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(entry, file)
+		return err
+	})
+}
+
+func isGitPackFile(relPath string) bool {
+	matched, _ := filepath.Match(filepath.Join("objects", "pack", "*.pack"), relPath)
+	return matched
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/google/go-github/github"
+	"github.com/motemen/ghq/utils"
+	"golang.org/x/oauth2"
+)
+
+// githubClient returns an authenticated GitHub API client when a
+// ghq.github.token git-config value is present, and an anonymous,
+// rate-limited client otherwise.
+func githubClient() *github.Client {
+	token, err := GitConfig("ghq.github.token")
+	utils.PanicIf(err)
+
+	if token == "" {
+		return github.NewClient(nil)
+	}
+
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	httpClient := oauth2.NewClient(oauth2.NoContext, tokenSource)
+	return github.NewClient(httpClient)
+}
+
+// authenticatedGithubClient behaves like githubClient, except that it will
+// interactively create a ghq.github.token (running the device authorization
+// flow) instead of falling back to an anonymous client.
+func authenticatedGithubClient() *github.Client {
+	token, err := findOrCreateGithubToken()
+	utils.PanicIf(err)
+
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	httpClient := oauth2.NewClient(oauth2.NoContext, tokenSource)
+	return github.NewClient(httpClient)
+}
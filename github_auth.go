@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/motemen/ghq/utils"
+)
+
+const githubDeviceCodeURL = "https://github.com/login/device/code"
+const githubAccessTokenURL = "https://github.com/login/oauth/access_token"
+
+// defaultGithubClientID is ghq's own registered OAuth App, used for the
+// device authorization flow. It can be pointed at a different app (e.g. an
+// enterprise GitHub Apps registration) via ghq.github.clientid.
+const defaultGithubClientID = "178c6fc778ccc68e1d6a"
+
+var githubTokenScopes = []string{"repo", "read:org"}
+
+type githubDeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+type githubAccessToken struct {
+	AccessToken string `json:"access_token"`
+	Error       string `json:"error"`
+}
+
+// findOrCreateGithubToken returns the ghq.github.token git-config value,
+// running the OAuth device authorization flow (the GitHub API has not
+// accepted Basic Auth for token creation in years, and 2FA is handled
+// entirely on github.com during this flow) the first time ghq needs
+// authenticated access. starred uses it through authenticatedGithubClient;
+// org uses githubClient, which reads an existing token but never runs this
+// flow.
+func findOrCreateGithubToken() (string, error) {
+	token, err := GitConfig("ghq.github.token")
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		return token, nil
+	}
+
+	token, err = githubDeviceFlowToken()
+	if err != nil {
+		return "", err
+	}
+
+	utils.Run("git", "config", "ghq.github.token", token)
+
+	return token, nil
+}
+
+func githubClientID() (string, error) {
+	clientID, err := GitConfig("ghq.github.clientid")
+	if err != nil {
+		return "", err
+	}
+	if clientID == "" {
+		clientID = defaultGithubClientID
+	}
+	return clientID, nil
+}
+
+func githubDeviceFlowToken() (string, error) {
+	clientID, err := githubClientID()
+	if err != nil {
+		return "", err
+	}
+
+	dc, err := requestGithubDeviceCode(clientID)
+	if err != nil {
+		return "", err
+	}
+
+	utils.Log("github", fmt.Sprintf("Open %s and enter code %s", dc.VerificationURI, dc.UserCode))
+
+	interval := time.Duration(dc.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(dc.ExpiresIn) * time.Second)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		token, pending, err := pollGithubAccessToken(clientID, dc.DeviceCode)
+		if err != nil {
+			return "", err
+		}
+		if !pending {
+			return token, nil
+		}
+	}
+
+	return "", fmt.Errorf("github: device authorization timed out; run the command again")
+}
+
+func requestGithubDeviceCode(clientID string) (*githubDeviceCode, error) {
+	form := url.Values{
+		"client_id": {clientID},
+		"scope":     {strings.Join(githubTokenScopes, " ")},
+	}
+
+	req, err := http.NewRequest("POST", githubDeviceCodeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github: could not start device authorization: %s", res.Status)
+	}
+
+	var dc githubDeviceCode
+	if err := json.NewDecoder(res.Body).Decode(&dc); err != nil {
+		return nil, err
+	}
+
+	return &dc, nil
+}
+
+// pollGithubAccessToken asks whether the user has approved the device code
+// yet. pending is true for both "authorization_pending" and "slow_down",
+// since the caller's fixed polling interval already starts conservative.
+func pollGithubAccessToken(clientID, deviceCode string) (token string, pending bool, err error) {
+	form := url.Values{
+		"client_id":   {clientID},
+		"device_code": {deviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+
+	req, err := http.NewRequest("POST", githubAccessTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer res.Body.Close()
+
+	var body githubAccessToken
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", false, err
+	}
+
+	switch body.Error {
+	case "":
+		return body.AccessToken, false, nil
+	case "authorization_pending", "slow_down":
+		return "", true, nil
+	default:
+		return "", false, fmt.Errorf("github: device authorization failed: %s", body.Error)
+	}
+}
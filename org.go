@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/codegangsta/cli"
+	"github.com/google/go-github/github"
+	"github.com/motemen/ghq/utils"
+)
+
+var OrgCommand = cli.Command{
+	Name:   "org",
+	Usage:  "Clone all repositories of a GitHub organization or user",
+	Action: DoOrg,
+	Flags: []cli.Flag{
+		cli.BoolFlag{"update, u", "Update local repository if cloned already"},
+		cli.StringSliceFlag{"include", &cli.StringSlice{}, "Only clone repositories whose name matches `GLOB` (may be repeated)"},
+		cli.StringSliceFlag{"exclude", &cli.StringSlice{}, "Skip repositories whose name matches `GLOB` (may be repeated, takes precedence over --include)"},
+		cli.BoolFlag{"include-forks", "Also clone forked repositories"},
+		cli.BoolFlag{"include-archived", "Also clone archived repositories"},
+		jobsFlag,
+		protocolFlag,
+	},
+}
+
+// repoCache dedupes repositories by their canonical <host>/<owner>/<name>
+// identity so that paging through an organization, running the same query
+// against several --include patterns, or chaining org with another bulk
+// command in the same process never clones the same physical repository
+// twice.
+type repoCache struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func newRepoCache() *repoCache {
+	return &repoCache{seen: map[string]bool{}}
+}
+
+func (c *repoCache) seenBefore(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.seen[key] {
+		return true
+	}
+	c.seen[key] = true
+	return false
+}
+
+// globalRepoCache is consulted by getRemoteRepositoryWithLogger so that
+// commands going through it (get, org, starred, pocket) in one process never
+// clone or update the same repository twice. backup does not consult it.
+var globalRepoCache = newRepoCache()
+
+func canonicalRepoKey(u *url.URL) string {
+	host := strings.ToLower(u.Host)
+	path := strings.ToLower(strings.TrimSuffix(u.Path, ".git"))
+	return host + path
+}
+
+func DoOrg(c *cli.Context) {
+	orgOrUser := c.Args().First()
+
+	if orgOrUser == "" {
+		cli.ShowCommandHelp(c, "org")
+		os.Exit(1)
+	}
+
+	includes := c.StringSlice("include")
+	excludes := c.StringSlice("exclude")
+	includeForks := c.Bool("include-forks")
+	includeArchived := c.Bool("include-archived")
+	doUpdate := c.Bool("update")
+
+	client := githubClient()
+	cache := newRepoCache()
+	queue := newCloneQueue(c.Int("jobs"))
+	protocol := resolveProtocol(c)
+
+	visit := func(repo *github.Repository) {
+		if repo.HTMLURL == nil {
+			return
+		}
+
+		if !includeForks && repo.Fork != nil && *repo.Fork {
+			return
+		}
+		if !includeArchived && repo.Archived != nil && *repo.Archived {
+			return
+		}
+
+		name := canonicalRepoName(repo)
+		if !matchesFilters(name, includes, excludes) {
+			return
+		}
+
+		htmlURL, err := url.Parse(*repo.HTMLURL)
+		if utils.ErrorIf(err) {
+			return
+		}
+
+		if cache.seenBefore(canonicalRepoKey(htmlURL)) {
+			return
+		}
+
+		remote, err := NewRemoteRepository(htmlURL)
+		if utils.ErrorIf(err) {
+			return
+		}
+
+		if remote.IsValid() == false {
+			utils.Log("error", fmt.Sprintf("Not a valid repository: %s", htmlURL))
+			return
+		}
+
+		queue.EnqueueClone(remote, doUpdate, protocol)
+	}
+
+	orgOptions := &github.RepositoryListByOrgOptions{}
+	for page := 1; ; page++ {
+		orgOptions.Page = page
+
+		repositories, res, err := client.Repositories.ListByOrg(orgOrUser, orgOptions)
+		if err != nil {
+			// Not every argument is an organization; fall back to the user
+			// listing below instead of dying here.
+			break
+		}
+
+		utils.Log("page", fmt.Sprintf("%d/%d", page, res.LastPage))
+		for _, repo := range repositories {
+			visit(&repo)
+		}
+
+		if page == res.LastPage {
+			queue.Wait()
+			return
+		}
+	}
+
+	userOptions := &github.RepositoryListOptions{}
+	for page := 1; ; page++ {
+		userOptions.Page = page
+
+		repositories, res, err := client.Repositories.List(orgOrUser, userOptions)
+		utils.DieIf(err)
+
+		utils.Log("page", fmt.Sprintf("%d/%d", page, res.LastPage))
+		for _, repo := range repositories {
+			visit(&repo)
+		}
+
+		if page == res.LastPage {
+			break
+		}
+	}
+
+	queue.Wait()
+}
+
+// canonicalRepoName returns repo's bare name (not owner-qualified), lowercased,
+// since --include/--exclude globs are matched with filepath.Match, whose "*"
+// never crosses the "/" in "owner/name".
+func canonicalRepoName(repo *github.Repository) string {
+	if repo.Name != nil {
+		return strings.ToLower(*repo.Name)
+	}
+	if repo.FullName != nil {
+		full := strings.ToLower(*repo.FullName)
+		if i := strings.LastIndex(full, "/"); i >= 0 {
+			return full[i+1:]
+		}
+		return full
+	}
+	return ""
+}
+
+func matchesFilters(name string, includes, excludes []string) bool {
+	for _, pattern := range excludes {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return false
+		}
+	}
+
+	if len(includes) == 0 {
+		return true
+	}
+
+	for _, pattern := range includes {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/codegangsta/cli"
+	"github.com/motemen/ghq/utils"
+	"golang.org/x/crypto/ssh"
+)
+
+var protocolFlag = cli.StringFlag{"protocol", "", "Clone protocol: https, ssh, or git (default ghq.protocol config, or https)"}
+
+// resolveProtocol returns the --protocol flag value, falling back to the
+// ghq.protocol git-config default, and finally to "https".
+func resolveProtocol(c *cli.Context) string {
+	protocol := c.String("protocol")
+	if protocol != "" {
+		return protocol
+	}
+
+	protocol, err := GitConfig("ghq.protocol")
+	utils.PanicIf(err)
+
+	if protocol == "" {
+		return "https"
+	}
+	return protocol
+}
+
+// rewriteURLForProtocol rewrites u to clone over protocol ("https", "ssh" or
+// "git"), honoring ghq.<host>.user to support self-hosted GitLab/Gitea
+// instances whose SSH user isn't "git".
+func rewriteURLForProtocol(u *url.URL, protocol string) (*url.URL, error) {
+	switch protocol {
+	case "", "https":
+		return u, nil
+
+	case "ssh":
+		user, err := GitConfig(fmt.Sprintf("ghq.%s.user", u.Host))
+		if err != nil {
+			return nil, err
+		}
+		if user == "" {
+			user = "git"
+		}
+
+		return &url.URL{
+			Scheme: "ssh",
+			User:   url.User(user),
+			Host:   u.Host,
+			Path:   u.Path,
+		}, nil
+
+	case "git":
+		// The anonymous, unauthenticated git:// transport; no user or key
+		// material is involved, unlike ssh.
+		return &url.URL{
+			Scheme: "git",
+			Host:   u.Host,
+			Path:   u.Path,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("ghq: unknown protocol %q (want https, ssh or git)", protocol)
+	}
+}
+
+// checkSSHAuth verifies that cloning over SSH for host has a usable
+// credential: an explicit ghq.<host>.sshkey, a default key under
+// ~/.ssh, or a running ssh-agent. It returns a clear error instead of
+// letting the clone fail deep inside the VCS backend.
+func checkSSHAuth(host string) error {
+	keyPath, err := GitConfig(fmt.Sprintf("ghq.%s.sshkey", host))
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		// An agent is available; it may hold the right key regardless of
+		// which key file (if any) is configured.
+		return nil
+	}
+
+	if keyPath == "" {
+		home := os.Getenv("HOME")
+		for _, name := range []string{"id_ed25519", "id_rsa"} {
+			candidate := filepath.Join(home, ".ssh", name)
+			if _, err := os.Stat(candidate); err == nil {
+				keyPath = candidate
+				break
+			}
+		}
+	}
+
+	if keyPath == "" {
+		return fmt.Errorf("ghq: no SSH key configured for %s (set ghq.%s.sshkey or start ssh-agent)", host, host)
+	}
+
+	if keyRequiresPassphrase(keyPath) {
+		return fmt.Errorf("ghq: SSH key %s is passphrase-protected and no ssh-agent (SSH_AUTH_SOCK) is running", keyPath)
+	}
+
+	return nil
+}
+
+func keyRequiresPassphrase(keyPath string) bool {
+	data, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return false
+	}
+
+	_, err = ssh.ParsePrivateKey(data)
+	if err == nil {
+		return false
+	}
+
+	_, encrypted := err.(*ssh.PassphraseMissingError)
+	return encrypted
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/motemen/ghq/utils"
+)
+
+// cloneQueue dispatches work across a bounded pool of workers, so bulk
+// commands (starred, pocket, org, backup) don't have to clone/update their
+// repositories one at a time.
+type cloneQueue struct {
+	jobs chan cloneJob
+	wg   sync.WaitGroup
+}
+
+// cloneJob is a unit of work keyed by the target directory it writes to, so
+// the queue can guard against two jobs racing onto the same path. run does
+// the actual work and reports through log instead of straight to utils.Log,
+// so its output can be buffered and flushed atomically.
+type cloneJob struct {
+	path string
+	run  func(log func(tag, message string))
+}
+
+// inFlightPaths guards against two jobs racing to write into the same
+// target directory, which can happen when two different remote URLs
+// resolve to the same local path, or the same URL appears twice in a
+// --from-list.
+var inFlightPaths sync.Map
+
+func newCloneQueue(workers int) *cloneQueue {
+	if workers < 1 {
+		workers = 1
+	}
+
+	q := &cloneQueue{jobs: make(chan cloneJob, workers*4)}
+
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+
+	return q
+}
+
+func (q *cloneQueue) work() {
+	for job := range q.jobs {
+		q.process(job)
+		q.wg.Done()
+	}
+}
+
+func (q *cloneQueue) process(job cloneJob) {
+	if _, inFlight := inFlightPaths.LoadOrStore(job.path, true); inFlight {
+		utils.Log("skip", job.path+" (already in flight)")
+		return
+	}
+	defer inFlightPaths.Delete(job.path)
+
+	logger := &bufferedLogger{}
+	job.run(logger.Log)
+	logger.Flush()
+}
+
+// Enqueue schedules run to execute on the queue, keyed by path for the
+// in-flight guard.
+func (q *cloneQueue) Enqueue(path string, run func(log func(tag, message string))) {
+	q.wg.Add(1)
+	q.jobs <- cloneJob{path: path, run: run}
+}
+
+// EnqueueClone is the common case: clone or update a single remote
+// repository.
+func (q *cloneQueue) EnqueueClone(remote RemoteRepository, doUpdate bool, protocol string) {
+	q.Enqueue(localRepositoryFor(remote).FullPath, func(log func(tag, message string)) {
+		getRemoteRepositoryWithLogger(remote, doUpdate, protocol, log)
+	})
+}
+
+func (q *cloneQueue) Wait() {
+	q.wg.Wait()
+	close(q.jobs)
+}
+
+// bufferedLogger collects Log calls made while processing a single job and
+// flushes them together, so that log lines for one repository are never
+// interleaved with another worker's output.
+type bufferedLogger struct {
+	mu    sync.Mutex
+	lines [][2]string
+}
+
+func (l *bufferedLogger) Log(tag, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, [2]string{tag, message})
+}
+
+func (l *bufferedLogger) Flush() {
+	l.mu.Lock()
+	lines := l.lines
+	l.lines = nil
+	l.mu.Unlock()
+
+	for _, line := range lines {
+		utils.Log(line[0], line[1])
+	}
+}